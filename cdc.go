@@ -0,0 +1,194 @@
+package masso
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+)
+
+// Content-defined chunking (CDC) cuts a stream into variable-sized
+// chunks based on the local content instead of fixed byte offsets, so
+// that an insert/delete near the start of a stream only perturbs the
+// chunks around the edit rather than every chunk after it. The
+// algorithm below is a FastCDC-style gear-free variant: a Rabin-style
+// polynomial rolling hash is evaluated over a sliding window, and a
+// chunk boundary is cut whenever the hash matches a target mask,
+// subject to minSize/maxSize bounds.
+const (
+	// cdcWindowSize is the width in bytes of the rolling hash window
+	// used to decide chunk boundaries.
+	cdcWindowSize = 64
+
+	// cdcDefaultAvgChunkSize is used whenever a caller passes avgSize <= 0.
+	cdcDefaultAvgChunkSize = 1 << 13 // 8KiB
+)
+
+// rollingHash maintains a Rabin-style polynomial hash over the last
+// cdcWindowSize bytes seen, updating in O(1) per byte as the window
+// slides forward.
+type rollingHash struct {
+	base   uint64
+	pow    uint64 // base^(cdcWindowSize-1), used to evict the oldest byte
+	window [cdcWindowSize]byte
+	filled int
+	pos    int
+	value  uint64
+}
+
+func newRollingHash() *rollingHash {
+	const base = uint64(1099511628211) // FNV-ish odd multiplier
+
+	pow := uint64(1)
+	for i := 0; i < cdcWindowSize-1; i++ {
+		pow *= base
+	}
+	return &rollingHash{base: base, pow: pow}
+}
+
+// roll folds in the next byte and returns the updated hash value. The
+// value only reflects a full window once at least cdcWindowSize bytes
+// have been rolled in; until then it behaves like a growing prefix hash.
+func (rh *rollingHash) roll(b byte) uint64 {
+	if rh.filled == cdcWindowSize {
+		oldest := rh.window[rh.pos]
+		rh.value = (rh.value-uint64(oldest)*rh.pow)*rh.base + uint64(b)
+	} else {
+		rh.filled++
+		rh.value = rh.value*rh.base + uint64(b)
+	}
+	rh.window[rh.pos] = b
+	rh.pos = (rh.pos + 1) % cdcWindowSize
+	return rh.value
+}
+
+// cdcParams holds the derived bounds and masks for a target average
+// chunk size, following FastCDC's normalized chunking scheme: a
+// stricter mask (more required zero bits) is used before avgSize is
+// reached to discourage premature cuts, and a looser mask (fewer
+// required zero bits) is used after avgSize to pull the chunk back
+// towards the target before maxSize is hit.
+type cdcParams struct {
+	avgSize int64
+	minSize int64
+	maxSize int64
+
+	maskStrict uint64
+	maskLoose  uint64
+}
+
+func newCDCParams(avgSize int64) *cdcParams {
+	if avgSize <= 0 {
+		avgSize = cdcDefaultAvgChunkSize
+	}
+
+	bits := uint(math.Log2(float64(avgSize)))
+	return &cdcParams{
+		avgSize:    avgSize,
+		minSize:    avgSize / 4,
+		maxSize:    avgSize * 8,
+		maskStrict: 1<<(bits+2) - 1,
+		maskLoose:  1<<(bits-2) - 1,
+	}
+}
+
+// generateZerothLevelCDC is the CDC analog of generateZerothLevel: it
+// produces zeroth-level Nodes whose boundaries fall on content-defined
+// cut points rather than fixed blockSize offsets.
+func generateZerothLevelCDC(r io.Reader, avgSize int64, h hash.Hash) ([]*Node, error) {
+	if r == nil {
+		return nil, errNilReader
+	}
+
+	params := newCDCParams(avgSize)
+	br := bufio.NewReader(r)
+	rh := newRollingHash()
+
+	slots := make([]*Node, 0, 100) // Arbitrary initial guess
+
+	byteIndex := uint64(0)
+	chunkStart := uint64(0)
+	chunkLen := int64(0)
+	h.Reset()
+
+	cut := func() {
+		slot := &Node{
+			Checksum:       fmt.Sprintf("%x", h.Sum(nil)),
+			startByteIndex: chunkStart,
+			endByteIndex:   byteIndex,
+		}
+		slots = append(slots, slot)
+
+		chunkStart = byteIndex
+		chunkLen = 0
+		h.Reset()
+	}
+
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return slots, err
+		}
+
+		h.Write([]byte{b})
+		byteIndex++
+		chunkLen++
+		value := rh.roll(b)
+
+		switch {
+		case chunkLen < params.minSize:
+			continue
+
+		case chunkLen >= params.maxSize:
+			cut()
+
+		default:
+			mask := params.maskStrict
+			if chunkLen >= params.avgSize {
+				mask = params.maskLoose
+			}
+			if value&mask == 0 {
+				cut()
+			}
+		}
+	}
+
+	if byteIndex > chunkStart {
+		cut()
+	}
+
+	return slots, nil
+}
+
+// MerklefyCDC is the content-defined-chunking sibling of Merklefy: it
+// builds a MerkleTree whose leaves follow content-defined boundaries
+// instead of fixed-size blocks, so that inserts/deletes near the
+// beginning of r don't invalidate every downstream leaf's checksum.
+// avgChunkSize is the target average chunk size in bytes; passing <= 0
+// falls back to cdcDefaultAvgChunkSize.
+func MerklefyCDC(r io.Reader, h hash.Hash, avgChunkSize int64) (*MerkleTree, error) {
+	mk := NewMerkleTree(h)
+
+	if err := mk.MerklefyCDC(r, avgChunkSize); err != nil {
+		return nil, err
+	}
+	return mk, nil
+}
+
+// MerklefyCDC rebuilds mk from r using content-defined chunking. See
+// the package-level MerklefyCDC for details.
+func (mk *MerkleTree) MerklefyCDC(r io.Reader, avgChunkSize int64) error {
+	theHash := mk.hash()
+	nodes, err := generateZerothLevelCDC(r, avgChunkSize, theHash)
+	if err != nil {
+		return err
+	}
+
+	mk.root = merklefy(nodes, theHash)
+	mk.index = mk.root.index()
+	return nil
+}
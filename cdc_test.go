@@ -0,0 +1,143 @@
+package masso
+
+import (
+	"bytes"
+	"hash"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func newBlake2b(t *testing.T) hash.Hash {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestGenerateZerothLevelCDC(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	data := make([]byte, 200000)
+	rng.Read(data)
+
+	avgSize := int64(1 << 12)
+
+	slots, err := generateZerothLevelCDC(bytes.NewReader(data), avgSize, newBlake2b(t))
+	if err != nil {
+		t.Fatalf("generateZerothLevelCDC: %v", err)
+	}
+	if len(slots) == 0 {
+		t.Fatal("expected at least one chunk")
+	}
+
+	var covered uint64
+	for i, slot := range slots {
+		if slot.StartIndex() != covered {
+			t.Fatalf("slot #%d: gap in coverage: got start=%d, want=%d", i, slot.StartIndex(), covered)
+		}
+		if slot.EndIndex() <= slot.StartIndex() {
+			t.Fatalf("slot #%d: non-positive length [%d, %d)", i, slot.StartIndex(), slot.EndIndex())
+		}
+		covered = slot.EndIndex()
+	}
+
+	if got, want := covered, uint64(len(data)); got != want {
+		t.Errorf("total coverage = %d, want %d", got, want)
+	}
+
+	// Re-chunking the same input must be deterministic.
+	again, err := generateZerothLevelCDC(bytes.NewReader(data), avgSize, newBlake2b(t))
+	if err != nil {
+		t.Fatalf("generateZerothLevelCDC (second pass): %v", err)
+	}
+	if got, want := len(again), len(slots); got != want {
+		t.Fatalf("chunking is not deterministic: got %d chunks, want %d", got, want)
+	}
+	for i := range slots {
+		if slots[i].Checksum != again[i].Checksum {
+			t.Errorf("slot #%d: checksum mismatch across runs: %q != %q", i, slots[i].Checksum, again[i].Checksum)
+		}
+	}
+}
+
+// TestCDCEditResilience guards the feature's headline property: an
+// edit near the start of a stream should only perturb the handful of
+// chunks around it, not every chunk downstream, because boundaries
+// are content-defined rather than fixed offsets.
+func TestCDCEditResilience(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	original := make([]byte, 200000)
+	rng.Read(original)
+
+	insertAt := 5
+	insertion := []byte("HELLOWORLD") // 10 bytes, arbitrary
+
+	modified := make([]byte, 0, len(original)+len(insertion))
+	modified = append(modified, original[:insertAt]...)
+	modified = append(modified, insertion...)
+	modified = append(modified, original[insertAt:]...)
+
+	avgSize := int64(1 << 12)
+	origChunks, err := generateZerothLevelCDC(bytes.NewReader(original), avgSize, newBlake2b(t))
+	if err != nil {
+		t.Fatalf("generateZerothLevelCDC(original): %v", err)
+	}
+	modChunks, err := generateZerothLevelCDC(bytes.NewReader(modified), avgSize, newBlake2b(t))
+	if err != nil {
+		t.Fatalf("generateZerothLevelCDC(modified): %v", err)
+	}
+
+	origChecksums := make(map[string]bool, len(origChunks))
+	for _, c := range origChunks {
+		origChecksums[c.Checksum] = true
+	}
+
+	unchanged := 0
+	for _, c := range modChunks {
+		if origChecksums[c.Checksum] {
+			unchanged++
+		}
+	}
+
+	// A single small insertion near the start should only disturb the
+	// chunk(s) around it; the rest of the stream should re-sync to the
+	// same boundaries and checksums. Bound loosely to avoid flakiness
+	// from exactly where the rolling hash happens to cut, but tight
+	// enough to catch a chunker that degenerates into rehashing
+	// everything downstream of the edit.
+	if total := len(modChunks); total-unchanged > 5 {
+		t.Errorf("edit resilience regressed: only %d/%d chunks after the insertion matched an original chunk checksum (want all but a handful near the edit)", unchanged, total)
+	}
+	if unchanged == 0 {
+		t.Fatal("no chunk after the insertion matched any original chunk — CDC looks like it's re-chunking the whole stream on every edit")
+	}
+}
+
+func TestMerklefyCDCLookup(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	data := make([]byte, 100000)
+	rng.Read(data)
+
+	h := newBlake2b(t)
+	slots, err := generateZerothLevelCDC(bytes.NewReader(data), 1<<11, h)
+	if err != nil {
+		t.Fatalf("generateZerothLevelCDC: %v", err)
+	}
+
+	tree, err := MerklefyCDC(bytes.NewReader(data), h, 1<<11)
+	if err != nil {
+		t.Fatalf("MerklefyCDC: %v", err)
+	}
+
+	for i, slot := range slots {
+		matches, err := tree.Lookup(slot.Checksum)
+		if err != nil {
+			t.Fatalf("slot #%d: Lookup: %v", i, err)
+		}
+		if len(matches) == 0 {
+			t.Errorf("slot #%d: checksum %q not found in tree", i, slot.Checksum)
+		}
+	}
+}
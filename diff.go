@@ -0,0 +1,109 @@
+package masso
+
+import "unsafe"
+
+// Range is a half-open byte range [Start, End) within a tree's
+// original input.
+type Range struct {
+	Start uint64
+	End   uint64
+}
+
+// Diff walks a and b's trees in tandem: whenever two nodes at the same
+// position have equal checksums, their whole subtree is reported as
+// matched and recursion stops there; whenever they differ, Diff
+// recurses into their children, bottoming out at leaves whose byte
+// ranges are reported as changed. This only lines up cleanly when a
+// and b share the same chunk boundaries (e.g. two fixed-blockSize
+// trees over inputs of the same length, or two CDC trees where the
+// edit didn't shift any upstream boundaries) -- for the general case
+// where chunk counts diverge, compare leaf checksum sets directly via
+// ChangedRanges instead.
+func Diff(a, b *MerkleTree) (matched []Range, changed []Range, err error) {
+	if a == nil || b == nil {
+		return nil, nil, errNilReader
+	}
+
+	first, second := lockOrder(a, b)
+	first.RLock()
+	defer first.RUnlock()
+	if second != first {
+		second.RLock()
+		defer second.RUnlock()
+	}
+
+	if a.root == nil || b.root == nil {
+		return nil, nil, errNotYetIndexed
+	}
+
+	diffNodes(a.root, b.root, &matched, &changed)
+	return matched, changed, nil
+}
+
+// lockOrder returns a and b ordered by pointer address (lower first)
+// so that two goroutines calling Diff(a, b) and Diff(b, a) concurrently
+// always take the two trees' RWMutexes in the same sequence. Without
+// this, a writer (e.g. Merklefy rebuilding one of the trees) queued in
+// between the first and second RLock in each goroutine can make the
+// pair deadlock on each other's locks -- the classic AB-BA case.
+func lockOrder(a, b *MerkleTree) (*MerkleTree, *MerkleTree) {
+	if uintptr(unsafe.Pointer(a)) <= uintptr(unsafe.Pointer(b)) {
+		return a, b
+	}
+	return b, a
+}
+
+func diffNodes(an, bn *Node, matched, changed *[]Range) {
+	switch {
+	case an == nil && bn == nil:
+		return
+
+	case an == nil:
+		*changed = append(*changed, Range{bn.StartIndex(), bn.EndIndex()})
+		return
+
+	case bn == nil:
+		*changed = append(*changed, Range{an.StartIndex(), an.EndIndex()})
+		return
+
+	case an.Checksum == bn.Checksum:
+		*matched = append(*matched, Range{an.StartIndex(), an.EndIndex()})
+		return
+	}
+
+	aLeaf := an.LeftChild == nil && an.RightChild == nil
+	bLeaf := bn.LeftChild == nil && bn.RightChild == nil
+	if aLeaf || bLeaf {
+		*changed = append(*changed, Range{an.StartIndex(), an.EndIndex()})
+		if an.StartIndex() != bn.StartIndex() || an.EndIndex() != bn.EndIndex() {
+			*changed = append(*changed, Range{bn.StartIndex(), bn.EndIndex()})
+		}
+		return
+	}
+
+	diffNodes(an.LeftChild, bn.LeftChild, matched, changed)
+	diffNodes(an.RightChild, bn.RightChild, matched, changed)
+}
+
+// ChangedRanges is the one-sided counterpart to Diff for when only
+// the remote's leaf checksums are available (not its whole tree): it
+// returns mk's leaf ranges whose checksum doesn't appear anywhere in
+// remoteLeafChecksums, i.e. the ranges a client would need to pull
+// (or push) to bring the two in sync.
+func (mk *MerkleTree) ChangedRanges(remoteLeafChecksums []string) []Range {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	remote := make(map[string]bool, len(remoteLeafChecksums))
+	for _, checksum := range remoteLeafChecksums {
+		remote[checksum] = true
+	}
+
+	var changed []Range
+	for _, leaf := range collectLeaves(mk.root) {
+		if !remote[leaf.Checksum] {
+			changed = append(changed, Range{leaf.StartIndex(), leaf.EndIndex()})
+		}
+	}
+	return changed
+}
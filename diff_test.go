@@ -0,0 +1,99 @@
+package masso
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffDetectsChangedRegion(t *testing.T) {
+	original := bytes.Repeat([]byte{0xAB}, 1000)
+
+	modified := make([]byte, len(original))
+	copy(modified, original)
+	for i := 400; i < 420; i++ {
+		modified[i] ^= 0xFF
+	}
+
+	a, err := Merklefy(bytes.NewReader(original), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy(a): %v", err)
+	}
+	b, err := Merklefy(bytes.NewReader(modified), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy(b): %v", err)
+	}
+
+	matched, changed, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+
+	if len(changed) == 0 {
+		t.Fatal("expected at least one changed range")
+	}
+	if len(matched) == 0 {
+		t.Fatal("expected at least one matched range")
+	}
+
+	foundEdit := false
+	for _, r := range changed {
+		if r.Start <= 400 && r.End >= 420 {
+			foundEdit = true
+		}
+	}
+	if !foundEdit {
+		t.Errorf("no changed range covers the edited [400,420) span: %+v", changed)
+	}
+}
+
+func TestDiffIdenticalTreesAreFullyMatched(t *testing.T) {
+	data := bytes.Repeat([]byte("same"), 250)
+
+	a, err := Merklefy(bytes.NewReader(data), blake2bHash(), 64)
+	if err != nil {
+		t.Fatalf("Merklefy(a): %v", err)
+	}
+	b, err := Merklefy(bytes.NewReader(data), blake2bHash(), 64)
+	if err != nil {
+		t.Fatalf("Merklefy(b): %v", err)
+	}
+
+	matched, changed, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected no changed ranges for identical trees, got %+v", changed)
+	}
+	if got, want := len(matched), 1; got != want {
+		t.Errorf("expected the whole tree to match at the root, got %d matched ranges", got)
+	}
+}
+
+func TestChangedRanges(t *testing.T) {
+	data := make([]byte, 500)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	tree, err := Merklefy(bytes.NewReader(data), blake2bHash(), 50)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	leaves := collectLeaves(tree.root)
+	var remote []string
+	for i, leaf := range leaves {
+		if i == 3 {
+			continue // pretend the remote is missing this chunk
+		}
+		remote = append(remote, leaf.Checksum)
+	}
+
+	changed := tree.ChangedRanges(remote)
+	if got, want := len(changed), 1; got != want {
+		t.Fatalf("got %d changed ranges, want %d: %+v", got, want, changed)
+	}
+	if changed[0] != (Range{leaves[3].StartIndex(), leaves[3].EndIndex()}) {
+		t.Errorf("changed range = %+v, want %+v", changed[0], Range{leaves[3].StartIndex(), leaves[3].EndIndex()})
+	}
+}
@@ -43,6 +43,20 @@ type MerkleTree struct {
 	root  *Node
 	index map[string][]*Node
 	_hash hash.Hash
+
+	// _hashFactory mints a private hash.Hash for each OutputReader,
+	// since _hash is shared by every tree-building call and can't
+	// safely be handed out to concurrent readers; see OutputReader in
+	// xof.go. A nil factory falls back to blake2bHash.
+	_hashFactory func() hash.Hash
+
+	// disk, diskHeader, diskLeafLevel and diskRootChecksum are set by
+	// LoadMerkleTree for trees backed directly by an on-disk buffer;
+	// see persist.go.
+	disk             io.ReaderAt
+	diskHeader       *diskHeader
+	diskLeafLevel    levelDescriptor
+	diskRootChecksum string
 }
 
 func (mk *MerkleTree) BreadthTraverse(fn func(*Node)) {
@@ -61,6 +75,13 @@ func (mk *MerkleTree) hash() hash.Hash {
 	mk.RLock()
 	defer mk.RUnlock()
 
+	return mk.hashLocked()
+}
+
+// hashLocked is hash's lock-free counterpart for callers that already
+// hold mk's lock; calling mk.hash() in that situation would recurse
+// on the RWMutex and can deadlock against a blocked writer.
+func (mk *MerkleTree) hashLocked() hash.Hash {
 	if mk == nil || mk._hash == nil {
 		return blake2bHash()
 	}
@@ -73,6 +94,24 @@ func (mk *MerkleTree) SetHash(h hash.Hash) {
 	mk.Unlock()
 }
 
+// SetHashFactory sets the factory OutputReader uses to mint a private
+// hash.Hash for each reader it hands out. A nil factory (the default)
+// falls back to blake2bHash.
+func (mk *MerkleTree) SetHashFactory(f func() hash.Hash) {
+	mk.Lock()
+	mk._hashFactory = f
+	mk.Unlock()
+}
+
+// hashFactoryLocked is like SetHashFactory's getter counterpart for
+// callers that already hold mk's lock.
+func (mk *MerkleTree) hashFactoryLocked() func() hash.Hash {
+	if mk == nil || mk._hashFactory == nil {
+		return blake2bHash
+	}
+	return mk._hashFactory
+}
+
 func NewMerkleTree(h hash.Hash) *MerkleTree {
 	return &MerkleTree{_hash: h}
 }
@@ -115,6 +154,10 @@ func (mk *MerkleTree) Lookup(checksum string) ([]*Node, error) {
 	mk.RLock()
 	defer mk.RUnlock()
 
+	if mk.disk != nil {
+		return mk.lookupDiskLocked(checksum)
+	}
+
 	if mk.index == nil {
 		return nil, errNotYetIndexed
 	}
@@ -122,6 +165,48 @@ func (mk *MerkleTree) Lookup(checksum string) ([]*Node, error) {
 	return mk.index[checksum], nil
 }
 
+// LookupOffset finds the leaf covering byte offset. For trees loaded
+// via LoadMerkleTree it binary-searches the on-disk leaf table
+// directly, without materializing the tree; for in-memory trees it
+// walks down from the root.
+func (mk *MerkleTree) LookupOffset(offset uint64) (*Node, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	if mk.disk != nil {
+		return mk.lookupOffsetDiskLocked(offset)
+	}
+
+	if mk.root == nil {
+		return nil, errNotYetIndexed
+	}
+	if leaf := leafAt(mk.root, offset); leaf != nil {
+		return leaf, nil
+	}
+	return nil, errNoMatchFound
+}
+
+// RootChecksum returns mk's root checksum. For an in-memory tree this
+// is root.checksum(); for a tree loaded via LoadMerkleTree it's the
+// checksum WriteTo persisted directly after the leaf table, since the
+// internal node levels needed to recompute it from leaves alone aren't
+// kept on disk.
+func (mk *MerkleTree) RootChecksum() string {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	return mk.rootChecksumLocked()
+}
+
+// rootChecksumLocked is RootChecksum's lock-free counterpart for
+// callers that already hold mk's lock.
+func (mk *MerkleTree) rootChecksumLocked() string {
+	if mk.disk != nil {
+		return mk.diskRootChecksum
+	}
+	return mk.root.checksum()
+}
+
 var errEmptyChecksum = errors.New("empty checksum")
 
 func (n *Node) consistent() error {
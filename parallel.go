@@ -0,0 +1,156 @@
+package masso
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+)
+
+var errNonPositiveBlockSize = errors.New("blockSize must be > 0")
+
+// Merklefier builds a MerkleTree by hashing a stream's fixed-size
+// blocks across a pool of workers, each holding its own hash.Hash,
+// instead of serializing every block through one shared instance the
+// way Merklefy does. This matters for fast hashes (BLAKE2b, BLAKE3)
+// over large inputs, where a single hash.Hash becomes the bottleneck.
+// Merklefy(r, h, blockSize) remains a thin, serial wrapper kept for
+// backwards compatibility; new callers hashing large inputs should
+// prefer Merklefier directly.
+type Merklefier struct {
+	// Concurrency is the number of hashing workers to run. Values <= 0
+	// fall back to 1.
+	Concurrency int
+
+	// HashFactory constructs a private hash.Hash for each worker. A
+	// nil HashFactory falls back to blake2bHash.
+	HashFactory func() hash.Hash
+}
+
+func (mf *Merklefier) concurrency() int {
+	if mf.Concurrency > 0 {
+		return mf.Concurrency
+	}
+	return 1
+}
+
+func (mf *Merklefier) hashFactory() func() hash.Hash {
+	if mf.HashFactory != nil {
+		return mf.HashFactory
+	}
+	return blake2bHash
+}
+
+type blockJob struct {
+	index int
+	data  []byte
+}
+
+type blockResult struct {
+	index    int
+	length   int
+	checksum string
+}
+
+// Merklefy reads r in blockSize blocks and builds a MerkleTree,
+// hashing blocks across mf's worker pool before reassembling the
+// leaves in their original order.
+func (mf *Merklefier) Merklefy(r io.Reader, blockSize int64) (*MerkleTree, error) {
+	nodes, err := mf.generateZerothLevel(r, blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	h := mf.hashFactory()()
+	mk := NewMerkleTree(h)
+	mk.root = merklefy(nodes, h)
+	mk.index = mk.root.index()
+	return mk, nil
+}
+
+func (mf *Merklefier) generateZerothLevel(r io.Reader, blockSize int64) ([]*Node, error) {
+	if r == nil {
+		return nil, errNilReader
+	}
+	if blockSize <= 0 {
+		return nil, errNonPositiveBlockSize
+	}
+
+	pool := &sync.Pool{
+		New: func() interface{} { return make([]byte, blockSize) },
+	}
+
+	concurrency := mf.concurrency()
+	jobs := make(chan blockJob, concurrency)
+	results := make(chan blockResult, concurrency)
+	hashFactory := mf.hashFactory()
+
+	var workers sync.WaitGroup
+	workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer workers.Done()
+			h := hashFactory()
+			for job := range jobs {
+				h.Reset()
+				h.Write(job.data)
+				results <- blockResult{
+					index:    job.index,
+					length:   len(job.data),
+					checksum: fmt.Sprintf("%x", h.Sum(nil)),
+				}
+				pool.Put(job.data[:cap(job.data)])
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			buf := pool.Get().([]byte)[:blockSize]
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				jobs <- blockJob{index: index, data: buf[:n]}
+			} else {
+				pool.Put(buf[:cap(buf)])
+			}
+			if err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					readErr = err
+				}
+				return
+			}
+		}
+	}()
+
+	var collected []blockResult
+	for res := range results {
+		collected = append(collected, res)
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	sort.Slice(collected, func(i, j int) bool { return collected[i].index < collected[j].index })
+
+	nodes := make([]*Node, 0, len(collected))
+	byteIndex := uint64(0)
+	for _, res := range collected {
+		endByteIndex := byteIndex + uint64(res.length)
+		nodes = append(nodes, &Node{
+			Checksum:       res.checksum,
+			startByteIndex: byteIndex,
+			endByteIndex:   endByteIndex,
+		})
+		byteIndex = endByteIndex
+	}
+
+	return nodes, nil
+}
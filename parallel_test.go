@@ -0,0 +1,59 @@
+package masso
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestMerklefierMatchesSerialMerklefy(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	data := make([]byte, 50000)
+	rng.Read(data)
+
+	serial, err := Merklefy(bytes.NewReader(data), blake2bHash(), 777)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	mf := &Merklefier{Concurrency: 8, HashFactory: blake2bHash}
+	parallel, err := mf.Merklefy(bytes.NewReader(data), 777)
+	if err != nil {
+		t.Fatalf("Merklefier.Merklefy: %v", err)
+	}
+
+	serialLeaves := collectLeaves(serial.root)
+	parallelLeaves := collectLeaves(parallel.root)
+
+	if got, want := len(parallelLeaves), len(serialLeaves); got != want {
+		t.Fatalf("leaf count = %d, want %d", got, want)
+	}
+	for i := range serialLeaves {
+		if serialLeaves[i].Checksum != parallelLeaves[i].Checksum {
+			t.Errorf("leaf #%d: checksum = %q, want %q", i, parallelLeaves[i].Checksum, serialLeaves[i].Checksum)
+		}
+		if serialLeaves[i].StartIndex() != parallelLeaves[i].StartIndex() || serialLeaves[i].EndIndex() != parallelLeaves[i].EndIndex() {
+			t.Errorf("leaf #%d: bounds = [%d,%d), want [%d,%d)", i,
+				parallelLeaves[i].StartIndex(), parallelLeaves[i].EndIndex(),
+				serialLeaves[i].StartIndex(), serialLeaves[i].EndIndex())
+		}
+	}
+
+	if serial.root.Checksum != parallel.root.Checksum {
+		t.Errorf("root checksum = %q, want %q", parallel.root.Checksum, serial.root.Checksum)
+	}
+}
+
+func TestMerklefierDefaultConcurrency(t *testing.T) {
+	mf := &Merklefier{}
+	if got, want := mf.concurrency(), 1; got != want {
+		t.Errorf("default concurrency = %d, want %d", got, want)
+	}
+}
+
+func TestMerklefierNonPositiveBlockSize(t *testing.T) {
+	mf := &Merklefier{}
+	if _, err := mf.Merklefy(bytes.NewReader([]byte("abc")), 0); err != errNonPositiveBlockSize {
+		t.Errorf("got %v, want errNonPositiveBlockSize", err)
+	}
+}
@@ -0,0 +1,349 @@
+package masso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+)
+
+// On-disk format:
+//
+//	[header][leaf table][root checksum][TOC][footer]
+//
+// The leaf table is a flat, fixed-width record table (checksum bytes,
+// start offset, end offset) sorted by start index, which is what lets
+// LookupOffset binary-search it directly against the underlying
+// buffer; internal tree levels aren't persisted since nothing reads
+// them back -- Proof and ProofAt both require an in-memory root and
+// return errNotYetIndexed for a disk-backed tree regardless. The
+// footer sits at the very end of the file so a loader only has to
+// read the tail to find and CRC-check the TOC before trusting
+// anything else.
+const (
+	diskMagic    = "MSSO"
+	diskTOCMagic = "MTOC"
+	diskVersion  = uint32(1)
+
+	hashAlgoUnknown    = uint32(0)
+	hashAlgoBlake2b256 = uint32(1)
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	errBadMagic           = errors.New("masso: bad magic number")
+	errUnsupportedVersion = errors.New("masso: unsupported on-disk version")
+	errTOCCorrupt         = errors.New("masso: TOC checksum mismatch")
+	errUnknownSize        = errors.New("masso: reader does not expose its size")
+	errDiskBackedNoRoot   = errors.New("masso: tree loaded via LoadMerkleTree has no in-memory root to serialize")
+)
+
+type diskHeader struct {
+	Magic        [4]byte
+	Version      uint32
+	HashAlgo     uint32
+	ChecksumSize uint32
+	LeafCount    uint64
+	TreeDepth    uint32
+}
+
+type diskFooter struct {
+	TOCOffset uint64
+	TOCSize   uint64
+	TOCCRC32  uint32
+	Magic     [4]byte
+}
+
+// levelDescriptor locates the leaf table within the file.
+type levelDescriptor struct {
+	Offset uint64
+	Count  uint64
+}
+
+var (
+	headerSize          = binary.Size(diskHeader{})
+	footerSize          = binary.Size(diskFooter{})
+	levelDescriptorSize = binary.Size(levelDescriptor{})
+)
+
+// collectLeaves returns n's leaves in left-to-right order.
+func collectLeaves(n *Node) []*Node {
+	if n == nil {
+		return nil
+	}
+	if n.LeftChild == nil && n.RightChild == nil {
+		return []*Node{n}
+	}
+	return append(collectLeaves(n.LeftChild), collectLeaves(n.RightChild)...)
+}
+
+// treeDepth returns the number of levels above the leaves in a tree
+// built by merklefy's pairing rule (pairs of two per level, an odd one
+// out carried up unchanged), given only the leaf count.
+func treeDepth(leafCount int) int {
+	depth := 0
+	for n := leafCount; n > 1; n = (n + 1) / 2 {
+		depth++
+	}
+	return depth
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+var _ io.WriterTo = (*MerkleTree)(nil)
+
+// WriteTo serializes mk to a compact binary format: a fixed header,
+// the leaf table, the root checksum, and a trailing TOC (with its own
+// CRC32C) that a loader can verify by reading only the tail. Internal
+// tree levels aren't persisted; see the format comment above. It
+// requires mk to already have been built (e.g. via Merklefy).
+func (mk *MerkleTree) WriteTo(w io.Writer) (int64, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	if mk.root == nil {
+		if mk.disk != nil {
+			return 0, errDiskBackedNoRoot
+		}
+		return 0, errNotYetIndexed
+	}
+
+	leaves := collectLeaves(mk.root)
+	checksumSize := mk.hashLocked().Size()
+
+	hashAlgo := uint32(hashAlgoUnknown)
+	if checksumSize == 32 {
+		hashAlgo = hashAlgoBlake2b256
+	}
+
+	header := diskHeader{
+		Version:      diskVersion,
+		HashAlgo:     hashAlgo,
+		ChecksumSize: uint32(checksumSize),
+		LeafCount:    uint64(len(leaves)),
+		TreeDepth:    uint32(treeDepth(len(leaves))),
+	}
+	copy(header.Magic[:], diskMagic)
+
+	cw := &countingWriter{w: w}
+	if err := binary.Write(cw, binary.BigEndian, header); err != nil {
+		return cw.n, err
+	}
+
+	leafLevel := levelDescriptor{Offset: uint64(cw.n), Count: uint64(len(leaves))}
+	for _, node := range leaves {
+		raw, err := hex.DecodeString(node.Checksum)
+		if err != nil || len(raw) != checksumSize {
+			return cw.n, fmt.Errorf("masso: malformed checksum %q", node.Checksum)
+		}
+		if _, err := cw.Write(raw); err != nil {
+			return cw.n, err
+		}
+		if err := binary.Write(cw, binary.BigEndian, node.StartIndex()); err != nil {
+			return cw.n, err
+		}
+		if err := binary.Write(cw, binary.BigEndian, node.EndIndex()); err != nil {
+			return cw.n, err
+		}
+	}
+
+	rootChecksum, err := hex.DecodeString(mk.root.checksum())
+	if err != nil || len(rootChecksum) != checksumSize {
+		return cw.n, fmt.Errorf("masso: malformed root checksum %q", mk.root.checksum())
+	}
+	if _, err := cw.Write(rootChecksum); err != nil {
+		return cw.n, err
+	}
+
+	var tocBuf bytes.Buffer
+	if err := binary.Write(&tocBuf, binary.BigEndian, leafLevel); err != nil {
+		return cw.n, err
+	}
+
+	footer := diskFooter{
+		TOCOffset: uint64(cw.n),
+		TOCSize:   uint64(tocBuf.Len()),
+		TOCCRC32:  crc32.Checksum(tocBuf.Bytes(), crc32cTable),
+	}
+	copy(footer.Magic[:], diskTOCMagic)
+
+	if _, err := cw.Write(tocBuf.Bytes()); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.BigEndian, footer); err != nil {
+		return cw.n, err
+	}
+
+	return cw.n, nil
+}
+
+// sizedReaderAt is implemented by readers that report their own length
+// via Size() int64 (e.g. *bytes.Reader, *strings.Reader,
+// *io.SectionReader).
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+func sizeOfReaderAt(ra io.ReaderAt) (int64, error) {
+	switch v := ra.(type) {
+	case sizedReaderAt:
+		return v.Size(), nil
+	case interface{ Stat() (fs.FileInfo, error) }:
+		fi, err := v.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	default:
+		return 0, errUnknownSize
+	}
+}
+
+// LoadMerkleTree parses the header, footer and TOC written by WriteTo
+// against ra (verifying the TOC's CRC32C) and returns a MerkleTree
+// backed directly by ra. Lookup and LookupOffset are served straight
+// from ra without ever materializing the full Node graph, so memory
+// use stays constant regardless of the original input size.
+func LoadMerkleTree(ra io.ReaderAt) (*MerkleTree, error) {
+	size, err := sizeOfReaderAt(ra)
+	if err != nil {
+		return nil, err
+	}
+
+	fbuf := make([]byte, footerSize)
+	if _, err := ra.ReadAt(fbuf, size-int64(footerSize)); err != nil {
+		return nil, err
+	}
+	var footer diskFooter
+	if err := binary.Read(bytes.NewReader(fbuf), binary.BigEndian, &footer); err != nil {
+		return nil, err
+	}
+	if string(footer.Magic[:]) != diskTOCMagic {
+		return nil, errBadMagic
+	}
+
+	tocBuf := make([]byte, footer.TOCSize)
+	if _, err := ra.ReadAt(tocBuf, int64(footer.TOCOffset)); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(tocBuf, crc32cTable) != footer.TOCCRC32 {
+		return nil, errTOCCorrupt
+	}
+
+	hbuf := make([]byte, headerSize)
+	if _, err := ra.ReadAt(hbuf, 0); err != nil {
+		return nil, err
+	}
+	var header diskHeader
+	if err := binary.Read(bytes.NewReader(hbuf), binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Magic[:]) != diskMagic {
+		return nil, errBadMagic
+	}
+	if header.Version != diskVersion {
+		return nil, errUnsupportedVersion
+	}
+
+	var leafLevel levelDescriptor
+	if err := binary.Read(bytes.NewReader(tocBuf), binary.BigEndian, &leafLevel); err != nil {
+		return nil, err
+	}
+
+	checksumSize := int64(header.ChecksumSize)
+	recordSize := checksumSize + 16
+	rootChecksumOffset := int64(leafLevel.Offset) + int64(leafLevel.Count)*recordSize
+	rootChecksumBuf := make([]byte, checksumSize)
+	if _, err := ra.ReadAt(rootChecksumBuf, rootChecksumOffset); err != nil {
+		return nil, err
+	}
+
+	mk := &MerkleTree{
+		disk:             ra,
+		diskHeader:       &header,
+		diskLeafLevel:    leafLevel,
+		diskRootChecksum: hex.EncodeToString(rootChecksumBuf),
+	}
+	if header.HashAlgo == hashAlgoBlake2b256 {
+		mk._hash = blake2bHash()
+	}
+
+	return mk, nil
+}
+
+// lookupDiskLocked scans the on-disk leaf table for checksum. Callers
+// must hold mk's lock and have already checked mk.disk != nil.
+func (mk *MerkleTree) lookupDiskLocked(checksum string) ([]*Node, error) {
+	want, err := hex.DecodeString(checksum)
+	if err != nil || len(want) != int(mk.diskHeader.ChecksumSize) {
+		return nil, nil
+	}
+
+	leafLevel := mk.diskLeafLevel
+	recordSize := int64(mk.diskHeader.ChecksumSize) + 16
+	buf := make([]byte, recordSize)
+
+	var matches []*Node
+	for i := uint64(0); i < leafLevel.Count; i++ {
+		if _, err := mk.disk.ReadAt(buf, int64(leafLevel.Offset)+int64(i)*recordSize); err != nil {
+			return matches, err
+		}
+		if bytes.Equal(buf[:mk.diskHeader.ChecksumSize], want) {
+			matches = append(matches, nodeFromRecord(checksum, buf, int(mk.diskHeader.ChecksumSize)))
+		}
+	}
+	return matches, nil
+}
+
+// lookupOffsetDiskLocked binary-searches the on-disk leaf table (which
+// is sorted by start index) for the leaf covering offset. Callers must
+// hold mk's lock and have already checked mk.disk != nil.
+func (mk *MerkleTree) lookupOffsetDiskLocked(offset uint64) (*Node, error) {
+	leafLevel := mk.diskLeafLevel
+	checksumSize := int(mk.diskHeader.ChecksumSize)
+	recordSize := int64(checksumSize) + 16
+	buf := make([]byte, recordSize)
+
+	lo, hi := uint64(0), leafLevel.Count
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := mk.disk.ReadAt(buf, int64(leafLevel.Offset)+int64(mid)*recordSize); err != nil {
+			return nil, err
+		}
+		start := binary.BigEndian.Uint64(buf[checksumSize : checksumSize+8])
+		end := binary.BigEndian.Uint64(buf[checksumSize+8 : checksumSize+16])
+
+		switch {
+		case offset < start:
+			hi = mid
+		case offset >= end:
+			lo = mid + 1
+		default:
+			return nodeFromRecord(hex.EncodeToString(buf[:checksumSize]), buf, checksumSize), nil
+		}
+	}
+	return nil, errNoMatchFound
+}
+
+func nodeFromRecord(checksum string, buf []byte, checksumSize int) *Node {
+	return &Node{
+		Checksum:       checksum,
+		startByteIndex: binary.BigEndian.Uint64(buf[checksumSize : checksumSize+8]),
+		endByteIndex:   binary.BigEndian.Uint64(buf[checksumSize+8 : checksumSize+16]),
+	}
+}
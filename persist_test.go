@@ -0,0 +1,75 @@
+package masso
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestWriteToLoadMerkleTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(99))
+	data := make([]byte, 10000)
+	rng.Read(data)
+
+	tree, err := Merklefy(bytes.NewReader(data), blake2bHash(), 123)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tree.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	loaded, err := LoadMerkleTree(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadMerkleTree: %v", err)
+	}
+
+	leaves := collectLeaves(tree.root)
+	for i, leaf := range leaves {
+		matches, err := loaded.Lookup(leaf.Checksum)
+		if err != nil {
+			t.Fatalf("leaf #%d: Lookup: %v", i, err)
+		}
+		if len(matches) == 0 {
+			t.Errorf("leaf #%d: checksum %q not found after round trip", i, leaf.Checksum)
+		}
+
+		got, err := loaded.LookupOffset(leaf.StartIndex())
+		if err != nil {
+			t.Fatalf("leaf #%d: LookupOffset(%d): %v", i, leaf.StartIndex(), err)
+		}
+		if got.Checksum != leaf.Checksum || got.StartIndex() != leaf.StartIndex() || got.EndIndex() != leaf.EndIndex() {
+			t.Errorf("leaf #%d: LookupOffset = %+v, want checksum=%q [%d,%d)", i, got, leaf.Checksum, leaf.StartIndex(), leaf.EndIndex())
+		}
+	}
+
+	if _, err := loaded.LookupOffset(uint64(len(data)) + 1000); err != errNoMatchFound {
+		t.Errorf("LookupOffset out of range: got %v, want errNoMatchFound", err)
+	}
+
+	if got, want := loaded.RootChecksum(), tree.RootChecksum(); got != want {
+		t.Errorf("RootChecksum after round trip = %q, want %q", got, want)
+	}
+}
+
+func TestLoadMerkleTreeCorruptTOC(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 500)
+	tree, err := Merklefy(bytes.NewReader(data), blake2bHash(), 50)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tree.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)-footerSize-1] ^= 0xff // flip the last byte of the TOC
+
+	if _, err := LoadMerkleTree(bytes.NewReader(corrupted)); err != errTOCCorrupt {
+		t.Errorf("LoadMerkleTree on corrupted data: got %v, want errTOCCorrupt", err)
+	}
+}
@@ -0,0 +1,106 @@
+package masso
+
+import "hash"
+
+// ProofStep is one rung in a Merkle proof: the checksum of the sibling
+// node encountered while walking from a leaf up to the root, and
+// whether that sibling sits on the left when concatenating for
+// checksumify.
+type ProofStep struct {
+	SiblingChecksum string
+	Left            bool
+}
+
+// Proof returns the path of ProofSteps from the leaf matching checksum
+// up to the root, suitable for passing to VerifyProof. If checksum
+// matches more than one leaf (duplicate content), the proof for the
+// first match found is returned, mirroring Lookup's own ambiguity.
+func (mk *MerkleTree) Proof(checksum string) ([]ProofStep, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	if mk.index == nil {
+		return nil, errNotYetIndexed
+	}
+
+	var leaf *Node
+	for _, n := range mk.index[checksum] {
+		if n.LeftChild == nil && n.RightChild == nil {
+			leaf = n
+			break
+		}
+	}
+	if leaf == nil {
+		return nil, errNoMatchFound
+	}
+
+	return proofFor(leaf), nil
+}
+
+// ProofAt returns the proof for the leaf covering byte offset index,
+// i.e. the leaf n for which n.StartIndex() <= index < n.EndIndex().
+func (mk *MerkleTree) ProofAt(index uint64) ([]ProofStep, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	if mk.root == nil {
+		return nil, errNotYetIndexed
+	}
+
+	leaf := leafAt(mk.root, index)
+	if leaf == nil {
+		return nil, errNoMatchFound
+	}
+
+	return proofFor(leaf), nil
+}
+
+func leafAt(n *Node, index uint64) *Node {
+	if n == nil || index < n.StartIndex() || index >= n.EndIndex() {
+		return nil
+	}
+	if n.LeftChild == nil && n.RightChild == nil {
+		return n
+	}
+	if leaf := leafAt(n.LeftChild, index); leaf != nil {
+		return leaf
+	}
+	return leafAt(n.RightChild, index)
+}
+
+// proofFor walks n up to the root via Parent, recording the sibling
+// checksum skipped at each level.
+func proofFor(n *Node) []ProofStep {
+	var steps []ProofStep
+	for cur := n; cur != nil && cur.Parent != nil; cur = cur.Parent {
+		parent := cur.Parent
+		switch cur {
+		case parent.LeftChild:
+			steps = append(steps, ProofStep{SiblingChecksum: parent.RightChild.checksum(), Left: false})
+		case parent.RightChild:
+			steps = append(steps, ProofStep{SiblingChecksum: parent.LeftChild.checksum(), Left: true})
+		}
+	}
+	return steps
+}
+
+// VerifyProof recomputes the root checksum by folding leaf up through
+// proof using the same concatenation rule as checksumify, and reports
+// whether the result matches root.
+func VerifyProof(root string, leaf string, proof []ProofStep, h hash.Hash) bool {
+	current := &Node{Checksum: leaf}
+
+	for _, step := range proof {
+		sibling := &Node{Checksum: step.SiblingChecksum}
+
+		var combined string
+		if step.Left {
+			combined = checksumify(sibling, current, h)
+		} else {
+			combined = checksumify(current, sibling, h)
+		}
+		current = &Node{Checksum: combined}
+	}
+
+	return current.Checksum == root
+}
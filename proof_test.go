@@ -0,0 +1,65 @@
+package masso
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestProofRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	data := make([]byte, 5000)
+	rng.Read(data)
+
+	h := blake2bHash()
+	tree, err := Merklefy(bytes.NewReader(data), h, 97)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	root := tree.root.Checksum
+
+	var leaves []*Node
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n == nil {
+			return
+		}
+		if n.LeftChild == nil && n.RightChild == nil {
+			leaves = append(leaves, n)
+			return
+		}
+		collect(n.LeftChild)
+		collect(n.RightChild)
+	}
+	collect(tree.root)
+
+	for i, leaf := range leaves {
+		proof, err := tree.Proof(leaf.Checksum)
+		if err != nil {
+			t.Fatalf("leaf #%d: Proof: %v", i, err)
+		}
+		if !VerifyProof(root, leaf.Checksum, proof, blake2bHash()) {
+			t.Errorf("leaf #%d: VerifyProof failed for checksum %q", i, leaf.Checksum)
+		}
+
+		byProofAt, err := tree.ProofAt(leaf.StartIndex())
+		if err != nil {
+			t.Fatalf("leaf #%d: ProofAt(%d): %v", i, leaf.StartIndex(), err)
+		}
+		if !VerifyProof(root, leaf.Checksum, byProofAt, blake2bHash()) {
+			t.Errorf("leaf #%d: VerifyProof via ProofAt failed", i)
+		}
+	}
+
+	if VerifyProof(root, "deadbeef", []ProofStep{{SiblingChecksum: "cafebabe", Left: true}}, blake2bHash()) {
+		t.Error("VerifyProof unexpectedly succeeded for a bogus leaf/proof")
+	}
+}
+
+func TestProofNotYetIndexed(t *testing.T) {
+	mk := NewMerkleTree(blake2bHash())
+	if _, err := mk.Proof("anything"); err != errNotYetIndexed {
+		t.Errorf("Proof on empty tree: got %v, want errNotYetIndexed", err)
+	}
+}
@@ -0,0 +1,99 @@
+package masso
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"math"
+)
+
+// merkleOutputReader is a keyed, seekable pseudorandom byte stream
+// derived from a MerkleTree's root checksum, analogous to a hash
+// function's XOF mode. The byte at absolute offset off is byte
+// off%blockSize of H(root || counter) with counter = off/blockSize,
+// where H is the reader's own hash.Hash (minted by the tree's hash
+// factory, see OutputReader) and blockSize is its Size(); Seek just moves
+// the cursor, it never recomputes earlier blocks.
+type merkleOutputReader struct {
+	root   string
+	h      hash.Hash
+	offset uint64
+}
+
+var _ io.ReadSeeker = (*merkleOutputReader)(nil)
+
+var errInvalidWhence = errors.New("masso: invalid whence")
+
+// OutputReader returns a keyed, seekable pseudorandom byte stream
+// derived from mk's root checksum. It's useful as a deterministic
+// KDF/PRNG bound to a specific tree -- e.g. deriving a per-file
+// encryption subkey or reproducible sampling positions -- without
+// needing a dedicated XOF-capable hash library. Seeking past the
+// current end of input is fine; the stream has no real end short of
+// SeekEnd's 2^64-1.
+//
+// Each call mints a fresh hash.Hash via mk's HashFactory (see
+// SetHashFactory; it falls back to blake2bHash), so independent
+// OutputReaders -- including ones read concurrently from different
+// goroutines -- never share mutable hash state.
+func (mk *MerkleTree) OutputReader() io.ReadSeeker {
+	mk.RLock()
+	defer mk.RUnlock()
+
+	return &merkleOutputReader{
+		root: mk.rootChecksumLocked(),
+		h:    mk.hashFactoryLocked()(),
+	}
+}
+
+func (r *merkleOutputReader) blockAt(counter uint64) []byte {
+	r.h.Reset()
+	io.WriteString(r.h, r.root)
+
+	var counterBuf [8]byte
+	binary.BigEndian.PutUint64(counterBuf[:], counter)
+	r.h.Write(counterBuf[:])
+
+	return r.h.Sum(nil)
+}
+
+func (r *merkleOutputReader) Read(p []byte) (int, error) {
+	blockSize := uint64(r.h.Size())
+
+	n := 0
+	for n < len(p) {
+		counter := r.offset / blockSize
+		within := r.offset % blockSize
+
+		block := r.blockAt(counter)
+		copied := copy(p[n:], block[within:])
+
+		n += copied
+		r.offset += uint64(copied)
+	}
+	return n, nil
+}
+
+// Seek moves the read cursor without recomputing any prior blocks.
+// SeekEnd treats the stream as having length 2^64-1; since that value
+// doesn't fit in the int64 the io.Seeker interface traffics in, the
+// returned offset wraps the same way the underlying uint64 cursor
+// does -- callers relying on SeekEnd should treat the returned value
+// as opaque and only feed it back into Seek(SeekStart).
+func (r *merkleOutputReader) Seek(offset int64, whence int) (int64, error) {
+	var base uint64
+	switch whence {
+	case io.SeekStart:
+		base = 0
+	case io.SeekCurrent:
+		base = r.offset
+	case io.SeekEnd:
+		base = math.MaxUint64
+	default:
+		return 0, errInvalidWhence
+	}
+
+	r.offset = base + uint64(offset)
+	return int64(r.offset), nil
+}
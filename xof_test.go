@@ -0,0 +1,121 @@
+package masso
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestOutputReaderDiskBacked guards against OutputReader keying the
+// PRNG off mk.root.checksum(), which is "" for a tree loaded via
+// LoadMerkleTree: a disk-backed tree's OutputReader must derive the
+// same byte stream as the in-memory tree it was persisted from.
+func TestOutputReaderDiskBacked(t *testing.T) {
+	tree, err := Merklefy(bytes.NewReader(bytes.Repeat([]byte("y"), 1000)), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tree.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	loaded, err := LoadMerkleTree(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadMerkleTree: %v", err)
+	}
+
+	want := make([]byte, 500)
+	if _, err := io.ReadFull(tree.OutputReader(), want); err != nil {
+		t.Fatalf("ReadFull(in-memory): %v", err)
+	}
+
+	got := make([]byte, 500)
+	if _, err := io.ReadFull(loaded.OutputReader(), got); err != nil {
+		t.Fatalf("ReadFull(disk-backed): %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("disk-backed tree's OutputReader diverges from the in-memory tree it was persisted from")
+	}
+}
+
+func TestOutputReaderDeterministic(t *testing.T) {
+	tree, err := Merklefy(bytes.NewReader(bytes.Repeat([]byte("z"), 1000)), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	a := make([]byte, 500)
+	if _, err := io.ReadFull(tree.OutputReader(), a); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+
+	b := make([]byte, 500)
+	if _, err := io.ReadFull(tree.OutputReader(), b); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+
+	if !bytes.Equal(a, b) {
+		t.Error("OutputReader is not deterministic across independent readers")
+	}
+}
+
+func TestOutputReaderSeek(t *testing.T) {
+	tree, err := Merklefy(bytes.NewReader(bytes.Repeat([]byte("q"), 1000)), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	r := tree.OutputReader()
+	full := make([]byte, 200)
+	if _, err := io.ReadFull(r, full); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+
+	if _, err := r.Seek(150, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	tail := make([]byte, 50)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+
+	if !bytes.Equal(tail, full[150:]) {
+		t.Errorf("seeking back and re-reading produced different bytes")
+	}
+
+	if _, err := r.Seek(0, 99); err != errInvalidWhence {
+		t.Errorf("Seek with bad whence: got %v, want errInvalidWhence", err)
+	}
+}
+
+// TestOutputReaderConcurrentIndependentReaders guards against
+// OutputReader handing out the tree's shared hash.Hash: run under
+// -race, two readers from the same tree used from different
+// goroutines must not race against each other.
+func TestOutputReaderConcurrentIndependentReaders(t *testing.T) {
+	tree, err := Merklefy(bytes.NewReader(bytes.Repeat([]byte("z"), 10000)), blake2bHash(), 100)
+	if err != nil {
+		t.Fatalf("Merklefy: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := tree.OutputReader()
+			buf := make([]byte, 2000)
+			for j := 0; j < 50; j++ {
+				if _, err := r.Read(buf); err != nil {
+					t.Errorf("Read: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}